@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/Ruboyi/smart-plug-scheduler/plug"
+	"github.com/Ruboyi/smart-plug-scheduler/tariff"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
 )
 
@@ -31,101 +39,223 @@ type PreciosLuz map[string]PrecioLuz
 
 // ObtenerPreciosLuz hace una solicitud a la API de precios de luz y devuelve los precios
 func ObtenerPreciosLuz(url string) (PreciosLuz, error) {
-	log.Println("Solicitando datos de precios de luz desde la API")
+	logEvento(EventoLog{Event: "price_api_request"})
+	temporizador := prometheus.NewTimer(priceAPIRequestSeconds)
+	defer temporizador.ObserveDuration()
+
 	resp, err := http.Get(url)
 	if err != nil {
+		logEvento(EventoLog{Event: "price_api_error", Err: err.Error()})
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		logEvento(EventoLog{Event: "price_api_error", Err: err.Error()})
 		return nil, err
 	}
 
 	var precios PreciosLuz
 	err = json.Unmarshal(body, &precios)
 	if err != nil {
+		logEvento(EventoLog{Event: "price_api_error", Err: err.Error()})
 		return nil, err
 	}
 
-	log.Println("Datos de precios de luz obtenidos correctamente")
+	logEvento(EventoLog{Event: "price_api_success"})
 	return precios, nil
 }
 
-// EncenderEnchufe hace una solicitud para encender el enchufe
-func EncenderEnchufe(url string) error {
-	log.Println("Enviando solicitud para encender el enchufe")
-	req, err := http.NewRequest("POST", url+"/encender", nil)
+// horaPrecio calcula el instante exacto (fecha + hora de inicio) al que
+// corresponde un PrecioLuz, necesario para resolver en qué zona tarifaria cae.
+func horaPrecio(precio PrecioLuz) time.Time {
+	horaInicio, err := strconv.Atoi(strings.Split(precio.Hour, "-")[0])
 	if err != nil {
-		return err
+		logEvento(EventoLog{Event: "hour_parse_error", Err: fmt.Sprintf("%s %s: %v", precio.Date, precio.Hour, err)})
+		return time.Time{}
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	instante, err := time.Parse("2006-01-02T15:04:05", fmt.Sprintf("%sT%02d:00:00", precio.Date, horaInicio))
 	if err != nil {
-		return fmt.Errorf("error al encender el enchufe: %w", err)
+		logEvento(EventoLog{Event: "hour_parse_error", Err: fmt.Sprintf("%s %s: %v", precio.Date, precio.Hour, err)})
+		return time.Time{}
 	}
-	defer resp.Body.Close()
+	return instante
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error al encender el enchufe: status code %d", resp.StatusCode)
+// precioEfectivo devuelve el precio a usar en la optimización: el precio
+// mayorista en bruto si no hay tarifa configurada, o el precio total
+// (mayorista + cargos + impuestos + recargo de zona) en caso contrario.
+func precioEfectivo(precio PrecioLuz, tarifa *tariff.Embed) float64 {
+	if tarifa == nil {
+		return precio.Price
 	}
+	return tarifa.TotalPrice(horaPrecio(precio), precio.Price)
+}
 
-	log.Println("Enchufe encendido correctamente")
-	return nil
+// Ventana representa un intervalo de encendido planificado por PlanificarVentanas.
+type Ventana struct {
+	HoraInicio string
+	HoraFin    string
+	// Duracion es el tiempo real que debe permanecer encendido el enchufe
+	// para esta ventana en concreto. Las ventanas forzadas por un Schedule
+	// pueden durar más o menos que SlotDuration, así que ProgramarVentanas
+	// debe usar este campo y no SlotDuration al programar el apagado.
+	Duracion    time.Duration
+	PrecioTotal float64
 }
 
-// ApagarEnchufe hace una solicitud para apagar el enchufe
-func ApagarEnchufe(url string) error {
-	log.Println("Enviando solicitud para apagar el enchufe")
-	req, err := http.NewRequest("POST", url+"/apagar", nil)
-	if err != nil {
-		return err
-	}
+// PlanOpts configura PlanificarVentanas.
+type PlanOpts struct {
+	// SlotDuration es la duración de cada ventana de encendido (antes fija a 3h).
+	SlotDuration time.Duration
+	// MaxDailyOn es el presupuesto diario de tiempo de encendido. No se planificará
+	// más tiempo que este total repartido entre todas las ventanas del día.
+	MaxDailyOn time.Duration
+	// MinGapBetween es el tiempo mínimo que debe dejarse entre el fin de una
+	// ventana y el inicio de la siguiente.
+	MinGapBetween time.Duration
+	// PreferOffPeakOnly descarta las horas que la API no marca como IsCheap
+	// antes de planificar.
+	PreferOffPeakOnly bool
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error al apagar el enchufe: %w", err)
-	}
-	defer resp.Body.Close()
+// dpEstado es el estado acumulado por la programación dinámica de PlanificarVentanas:
+// primero se maximiza el número de ventanas (para agotar el presupuesto diario) y,
+// a igualdad de ventanas, se minimiza el precio total.
+type dpEstado struct {
+	ventanas int
+	precio   float64
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error al apagar el enchufe: status code %d", resp.StatusCode)
+// mejorOIgual indica si a es al menos tan bueno como b, dando preferencia a a en
+// caso de empate (esto hace que, ante precios iguales, se prefiera la hora más
+// temprana al reconstruir la solución).
+func (a dpEstado) mejorOIgual(b dpEstado) bool {
+	if a.ventanas != b.ventanas {
+		return a.ventanas > b.ventanas
 	}
-
-	log.Println("Enchufe apagado correctamente")
-	return nil
+	return a.precio <= b.precio
 }
 
-// EncontrarRangoMasBarato encuentra el rango de 3 horas consecutivas más barato del día
-func EncontrarRangoMasBarato(precios PreciosLuz) (horaInicio string, horaFin string) {
+// PlanificarVentanas calcula, mediante programación dinámica sobre las franjas
+// horarias del día, el conjunto de ventanas de encendido de duración SlotDuration
+// con el mayor número de ventanas que cabe en el presupuesto diario MaxDailyOn
+// y, entre los conjuntos con ese número de ventanas, el de menor precio total
+// (tarifa incluida si se proporciona tarifa), sin dejar menos de MinGapBetween
+// entre ventanas consecutivas. Cada ventana ocupa horas consecutivas y no
+// cruza ninguna hora descartada. Si se proporciona sched, las horas en modo Deny
+// se excluyen de la optimización y las horas en modo Force se añaden siempre,
+// sin contar para el presupuesto diario.
+//
+// precios contiene únicamente las horas del día que ha devuelto la última
+// llamada a ObtenerPreciosLuz; esta función no obtiene ni combina los precios
+// de mañana, así que deliberadamente no puede formar ventanas que crucen la
+// medianoche (p. ej. 23:00-01:00). Queda pendiente de una futura petición que
+// añada la obtención y fusión de los precios del día siguiente.
+func PlanificarVentanas(precios PreciosLuz, opts PlanOpts, tarifa *tariff.Embed, sched *Schedule) []Ventana {
 	var listaPrecios []PrecioLuz
 	for _, precio := range precios {
+		if opts.PreferOffPeakOnly && !precio.IsCheap {
+			continue
+		}
+		if sched != nil {
+			if modo := sched.Evaluate(horaPrecio(precio)); modo == Deny || modo == Force {
+				continue
+			}
+		}
 		listaPrecios = append(listaPrecios, precio)
 	}
-
-	// Ordenar listaPrecios por la hora de inicio
 	sort.Slice(listaPrecios, func(i, j int) bool {
 		return listaPrecios[i].Hour < listaPrecios[j].Hour
 	})
 
-	minPrecio := float64(1<<63 - 1) // Un número muy grande
-	var inicio int
+	var forzadas []Ventana
+	if sched != nil {
+		forzadas = ventanasForzadas(precios, tarifa, sched)
+	}
+
+	n := len(listaPrecios)
+	L := int(opts.SlotDuration.Hours())
+	G := int(opts.MinGapBetween.Hours())
+	presupuesto := int(opts.MaxDailyOn.Hours())
+	if L <= 0 || n < L || presupuesto < L {
+		// No hay suficientes franjas para formar ni una sola ventana por precio,
+		// pero las forzadas por el Schedule se mantienen.
+		return forzadas
+	}
 
-	// Iterar sobre los precios para encontrar el rango de 3 horas consecutivas más barato
-	for i := 0; i <= len(listaPrecios)-3; i++ {
-		sumaPrecios := listaPrecios[i].Price + listaPrecios[i+1].Price + listaPrecios[i+2].Price
-		if sumaPrecios < minPrecio {
-			minPrecio = sumaPrecios
-			inicio = i
+	// dp[i][b] = mejor dpEstado alcanzable considerando las franjas desde i en
+	// adelante con b horas de presupuesto restante.
+	dp := make([][]dpEstado, n+1)
+	elige := make([][]bool, n+1)
+	for i := range dp {
+		dp[i] = make([]dpEstado, presupuesto+1)
+		elige[i] = make([]bool, presupuesto+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		// Una ventana solo puede empezar en i si sus L franjas son horas
+		// consecutivas: las horas descartadas (Deny, Force o no baratas con
+		// PreferOffPeakOnly) dejan huecos en listaPrecios que no debe cruzar.
+		consecutivas := i+L <= n &&
+			horaPrecio(listaPrecios[i+L-1]).Sub(horaPrecio(listaPrecios[i])) == time.Duration(L-1)*time.Hour
+		for b := 0; b <= presupuesto; b++ {
+			mejor := dp[i+1][b]
+			if consecutivas && b >= L {
+				siguiente := i + L + G
+				if siguiente > n {
+					siguiente = n
+				}
+				suma := 0.0
+				for k := i; k < i+L; k++ {
+					suma += precioEfectivo(listaPrecios[k], tarifa)
+				}
+				candidata := dpEstado{
+					ventanas: dp[siguiente][b-L].ventanas + 1,
+					precio:   dp[siguiente][b-L].precio + suma,
+				}
+				if candidata.mejorOIgual(mejor) {
+					mejor = candidata
+					elige[i][b] = true
+				}
+			}
+			dp[i][b] = mejor
 		}
 	}
 
-	horaInicio = listaPrecios[inicio].Hour
-	horaFin = listaPrecios[inicio+2].Hour
-	return horaInicio, horaFin
+	var ventanas []Ventana
+	i, b := 0, presupuesto
+	for i < n {
+		if elige[i][b] {
+			suma := 0.0
+			for k := i; k < i+L; k++ {
+				suma += precioEfectivo(listaPrecios[k], tarifa)
+			}
+			ventanas = append(ventanas, Ventana{
+				HoraInicio:  listaPrecios[i].Hour,
+				HoraFin:     listaPrecios[i+L-1].Hour,
+				Duracion:    opts.SlotDuration,
+				PrecioTotal: suma,
+			})
+			siguiente := i + L + G
+			if siguiente > n {
+				siguiente = n
+			}
+			b -= L
+			i = siguiente
+		} else {
+			i++
+		}
+	}
+
+	ventanas = append(ventanas, forzadas...)
+	sort.Slice(ventanas, func(i, j int) bool {
+		return ventanas[i].HoraInicio < ventanas[j].HoraInicio
+	})
+
+	return ventanas
 }
 
 // ConvierteHora convierte una hora en formato "hh-hh" a "15:04"
@@ -134,47 +264,138 @@ func ConvierteHora(hora string) string {
 	return partes[0] + ":00"
 }
 
-// ProgramarEncendido programa el encendido y apagado del enchufe
-func ProgramarEncendido(horaInicio string, horaFin string, enchufeURL string) {
+// scheduler mantiene los temporizadores de encendido y apagado pendientes.
+// Reutilizar un único Scheduler evita que el cron diario, al reprogramar,
+// deje goroutines o timers anteriores corriendo en segundo plano.
+var scheduler = NewScheduler()
+
+// ventanasProgramadasActivas recuerda los ids de Scheduler en uso por la
+// planificación actual, para poder cancelarlos todos cuando se reemplaza.
+// El cron de actualizarYProgramar y el servidor de administración acceden a
+// ella desde goroutines distintas, por lo que toda lectura o escritura debe
+// hacerse con ventanasProgramadasMu.
+var (
+	ventanasProgramadasMu      sync.Mutex
+	ventanasProgramadasActivas []string
+)
+
+// CancelarEncendidosProgramados cancela en el Scheduler todos los encendidos
+// y apagados planificados que siguen pendientes o en curso, evitando que se
+// acumulen al volver a llamar a actualizarYProgramar.
+func CancelarEncendidosProgramados() {
+	ventanasProgramadasMu.Lock()
+	ids := ventanasProgramadasActivas
+	ventanasProgramadasActivas = nil
+	ventanasProgramadasMu.Unlock()
+
+	for _, id := range ids {
+		scheduler.Cancel(id)
+	}
+	ReiniciarEventos()
+}
+
+// proximoPendiente devuelve el primer id de ventanasProgramadasActivas con el
+// prefijo dado ("encendido:" o "apagado:") que sigue pendiente en el
+// Scheduler, saltándose los que ya dispararon o se cancelaron. Como
+// ProgramarVentanas añade las ventanas en orden cronológico, es el próximo
+// encendido o apagado pendiente.
+func proximoPendiente(prefijo string) (string, bool) {
+	ventanasProgramadasMu.Lock()
+	defer ventanasProgramadasMu.Unlock()
+
+	for _, id := range ventanasProgramadasActivas {
+		if !strings.HasPrefix(id, prefijo) {
+			continue
+		}
+		scheduler.mu.Lock()
+		_, pendiente := scheduler.pendientes[id]
+		scheduler.mu.Unlock()
+		if pendiente {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// conmutarEnchufe enciende o apaga el enchufe según estado ("on" u "off") y
+// registra el resultado en las métricas Prometheus y en el log de eventos
+// JSON, incluyendo precio si se conoce (0 si no, p. ej. en un override manual
+// sin ventana planificada asociada). La usan tanto las acciones programadas
+// por ProgramarEncendido como el endpoint POST /override, para que ambos
+// caminos queden instrumentados igual.
+func conmutarEnchufe(ctx context.Context, driver plug.Driver, estado string, horaInicio, horaFin time.Time, precio float64) error {
+	var err error
+	if estado == "on" {
+		err = driver.On(ctx)
+	} else {
+		err = driver.Off(ctx)
+	}
+
+	registrarConmutacion(estado, err)
+	evento := EventoLog{Event: "plug_" + estado, HourStart: horaInicio.Format(time.RFC3339), HourEnd: horaFin.Format(time.RFC3339), Price: precio, Driver: driver.Name()}
+	if err != nil {
+		evento.Err = err.Error()
+	}
+	logEvento(evento)
+	return err
+}
+
+// ProgramarEncendido programa, a través del Scheduler, el encendido del
+// enchufe a horaInicio y su apagado duracion más tarde, usando driver. precio
+// es el precio total de la ventana, registrado en el evento de log emitido
+// al conmutar.
+func ProgramarEncendido(horaInicio string, duracion time.Duration, precio float64, driver plug.Driver) {
 	horaInicio = ConvierteHora(horaInicio)
-	
+
 	// Obtener la fecha de hoy y combinarla con la hora de inicio
 	now := time.Now()
 	hoyInicio := fmt.Sprintf("%d-%02d-%02dT%s:00Z", now.Year(), now.Month(), now.Day(), horaInicio)
 	horaInicioTime, err := time.Parse(time.RFC3339, hoyInicio)
 	if err != nil {
-		log.Println("Error al parsear la hora de inicio:", err)
+		logEvento(EventoLog{Event: "schedule_parse_error", Err: err.Error()})
 		return
 	}
 
-	log.Printf("Hora de inicio: %s", horaInicioTime.Format("15:04"))
-
 	// Verificar que la hora de inicio sea en el futuro
 	if horaInicioTime.Before(now) {
-		log.Println("La hora de inicio ya ha pasado, no se puede programar el encendido.")
+		logEvento(EventoLog{Event: "schedule_skipped_past", HourStart: horaInicioTime.Format(time.RFC3339)})
 		return
 	}
 
-	horaApagadoTime := horaInicioTime.Add(3 * time.Hour)
+	horaApagadoTime := horaInicioTime.Add(duracion)
 
-	log.Printf("Programando encendido del enchufe a las %s y apagado a las %s", horaInicioTime.Format("15:04"), horaApagadoTime.Format("15:04"))
+	logEvento(EventoLog{Event: "schedule_planned", HourStart: horaInicioTime.Format(time.RFC3339), HourEnd: horaApagadoTime.Format(time.RFC3339), Price: precio})
 
-	go func(encendido, apagado time.Time) {
-		log.Println("Esperando hasta la hora de encendido")
-		time.Sleep(time.Until(encendido))
-		if err := EncenderEnchufe(enchufeURL); err != nil {
-			log.Println(err)
-			return
-		}
+	RegistrarEvento(horaInicioTime, "on")
+	RegistrarEvento(horaApagadoTime, "off")
+	registrarProximaConmutacion("on", horaInicioTime)
+	registrarProximaConmutacion("off", horaApagadoTime)
 
-		log.Println("Enchufe encendido")
+	idEncendido := "encendido:" + horaInicioTime.Format(time.RFC3339)
+	idApagado := "apagado:" + horaApagadoTime.Format(time.RFC3339)
+	ventanasProgramadasMu.Lock()
+	ventanasProgramadasActivas = append(ventanasProgramadasActivas, idEncendido, idApagado)
+	ventanasProgramadasMu.Unlock()
 
-		time.Sleep(3 * time.Hour)
-		if err := ApagarEnchufe(enchufeURL); err != nil {
-			log.Println(err)
-		}
-		log.Println("Enchufe apagado")
-	}(horaInicioTime, horaApagadoTime)
+	scheduler.Set(idEncendido, horaInicioTime, func(ctx context.Context) {
+		conmutarEnchufe(ctx, driver, "on", horaInicioTime, horaApagadoTime, precio)
+	})
+
+	scheduler.Set(idApagado, horaApagadoTime, func(ctx context.Context) {
+		conmutarEnchufe(ctx, driver, "off", horaInicioTime, horaApagadoTime, precio)
+	})
+}
+
+// ProgramarVentanas cancela cualquier encendido previamente planificado y
+// programa en el Scheduler el encendido y apagado de cada ventana devuelta
+// por PlanificarVentanas, respetando la Duracion propia de cada ventana (que
+// puede diferir de SlotDuration para las ventanas forzadas por un Schedule).
+func ProgramarVentanas(ventanas []Ventana, driver plug.Driver) {
+	CancelarEncendidosProgramados()
+	for _, ventana := range ventanas {
+		plannedWindowPrice.Set(ventana.PrecioTotal)
+		ProgramarEncendido(ventana.HoraInicio, ventana.Duracion, ventana.PrecioTotal, driver)
+	}
 }
 
 func main() {
@@ -185,30 +406,53 @@ func main() {
 	}
 
 	preciosLuzAPI := os.Getenv("PRECIOS_LUZ_API")
-	enchufeAPI := os.Getenv("ENCHUFE_API")
-
-	if preciosLuzAPI == "" || enchufeAPI == "" {
-		log.Fatal("Las variables de entorno PRECIOS_LUZ_API y ENCHUFE_API deben estar definidas")
+	if preciosLuzAPI == "" {
+		log.Fatal("La variable de entorno PRECIOS_LUZ_API debe estar definida")
 	}
-
 	if !(startsWith(preciosLuzAPI, "http://") || startsWith(preciosLuzAPI, "https://")) {
 		log.Fatal("PRECIOS_LUZ_API debe empezar con 'http://' o 'https://'")
 	}
-	if !(startsWith(enchufeAPI, "http://") || startsWith(enchufeAPI, "https://")) {
-		log.Fatal("ENCHUFE_API debe empezar con 'http://' o 'https://'")
+
+	driver, err := plug.NewDriverFromEnv()
+	if err != nil {
+		log.Fatal("Error al configurar el driver del enchufe: ", err)
+	}
+
+	opts := PlanOpts{
+		SlotDuration:      horasEnv("SLOT_DURATION_HORAS", 3),
+		MaxDailyOn:        horasEnv("MAX_DAILY_ON_HORAS", 3),
+		MinGapBetween:     horasEnv("MIN_GAP_HORAS", 0),
+		PreferOffPeakOnly: os.Getenv("PREFER_OFF_PEAK_ONLY") == "true",
+	}
+
+	tarifa, err := tariff.LoadFromEnv()
+	if err != nil {
+		logEvento(EventoLog{Event: "tariff_load_error", Err: err.Error()})
+		tarifa = nil
+	}
+
+	sched := NewSchedule()
+	if puerto := os.Getenv("ADMIN_PORT"); puerto != "" {
+		IniciarServidorAdmin(":"+puerto, sched, driver)
+	}
+	if puerto := os.Getenv("METRICS_PORT"); puerto != "" {
+		IniciarServidorMetrics(":" + puerto)
 	}
 
 	// Función para obtener precios y programar encendido
 	actualizarYProgramar := func() {
-		log.Println("Actualizando precios y programando encendido del enchufe")
+		logEvento(EventoLog{Event: "schedule_cycle_start"})
 		precios, err := ObtenerPreciosLuz(preciosLuzAPI)
 		if err != nil {
-			log.Println("Error al obtener los precios de luz:", err)
 			return
 		}
 
-		horaInicio, horaFin := EncontrarRangoMasBarato(precios)
-		ProgramarEncendido(horaInicio, horaFin, enchufeAPI)
+		ventanas := PlanificarVentanas(precios, opts, tarifa, sched)
+		if len(ventanas) == 0 {
+			logEvento(EventoLog{Event: "schedule_empty"})
+			return
+		}
+		ProgramarVentanas(ventanas, driver)
 	}
 
 	// Actualizar y programar encendido al iniciar
@@ -219,13 +463,39 @@ func main() {
 	c.AddFunc("@daily", actualizarYProgramar)
 	c.Start()
 
-	log.Println("El programa está en ejecución")
+	logEvento(EventoLog{Event: "program_started"})
+
+	// Esperar a una señal de apagado y dar tiempo a que termine la acción en
+	// curso del Scheduler antes de salir.
+	señales := make(chan os.Signal, 1)
+	signal.Notify(señales, syscall.SIGINT, syscall.SIGTERM)
+	<-señales
 
-	// Para mantener el programa en ejecución
-	select {}
+	logEvento(EventoLog{Event: "shutdown_signal_received"})
+	ctx, cancelar := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelar()
+	if err := scheduler.Shutdown(ctx); err != nil {
+		logEvento(EventoLog{Event: "shutdown_timeout", Err: err.Error()})
+	}
 }
 
 // startsWith verifica si una cadena empieza con un prefijo dado
 func startsWith(str, prefix string) bool {
 	return len(str) >= len(prefix) && str[:len(prefix)] == prefix
 }
+
+// horasEnv lee una variable de entorno que representa un número de horas y la
+// convierte a time.Duration, devolviendo porDefecto si no está definida o no
+// es un número válido.
+func horasEnv(nombre string, porDefecto float64) time.Duration {
+	valor := os.Getenv(nombre)
+	if valor == "" {
+		return time.Duration(porDefecto * float64(time.Hour))
+	}
+	horas, err := strconv.ParseFloat(valor, 64)
+	if err != nil {
+		logEvento(EventoLog{Event: "config_invalid_value", Err: fmt.Sprintf("%s: usando %.1fh por defecto", nombre, porDefecto)})
+		return time.Duration(porDefecto * float64(time.Hour))
+	}
+	return time.Duration(horas * float64(time.Hour))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ruboyi/smart-plug-scheduler/plug"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// IniciarServidorAdmin levanta un servidor HTTP de administración en addr con
+// las rutas GET/PUT /schedule, GET /next, POST /override y también GET
+// /metrics por comodidad, para poder cambiar el comportamiento del
+// programador y vigilarlo sin reiniciar el proceso ni volver a editar el
+// .env. Las métricas también están disponibles sin este servidor a través de
+// IniciarServidorMetrics y METRICS_PORT.
+func IniciarServidorAdmin(addr string, sched *Schedule, driver plug.Driver) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/schedule", manejarSchedule(sched))
+	mux.HandleFunc("/next", manejarNext)
+	mux.HandleFunc("/override", manejarOverride(driver))
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		logEvento(EventoLog{Event: "admin_server_started"})
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logEvento(EventoLog{Event: "admin_server_error", Err: err.Error()})
+		}
+	}()
+	return srv
+}
+
+// manejarSchedule expone el Schedule actual (GET) o lo reemplaza por completo (PUT).
+func manejarSchedule(sched *Schedule) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(sched.Entries())
+		case http.MethodPut:
+			var entradas []EntradaSchedule
+			if err := json.NewDecoder(r.Body).Decode(&entradas); err != nil {
+				http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			sched.Set(entradas)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "método no permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// manejarNext devuelve los próximos encendidos y apagados planificados.
+func manejarNext(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ProximosEventos())
+}
+
+// overrideRequest es el cuerpo esperado por POST /override.
+type overrideRequest struct {
+	Estado string `json:"estado"` // "on" u "off"
+}
+
+// manejarOverride ejecuta un encendido o apagado manual inmediato, sin
+// esperar a la próxima ventana planificada, conmutando el enchufe
+// directamente y de forma síncrona: la respuesta HTTP refleja el resultado
+// real de driver.On/Off, con la misma instrumentación (métricas Prometheus y
+// evento de log) que usa ProgramarEncendido, para que nunca diverjan del
+// estado real del enchufe. Si ya había un encendido o apagado pendiente en
+// el Scheduler para ese mismo estado, queda ahora redundante: se cancela y
+// se retira también de eventosProgramados, para que GET /next deje de
+// anunciarlo como si fuera a ocurrir todavía.
+func manejarOverride(driver plug.Driver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var peticion overrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&peticion); err != nil {
+			http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var prefijo string
+		switch peticion.Estado {
+		case "on":
+			prefijo = "encendido:"
+		case "off":
+			prefijo = "apagado:"
+		default:
+			http.Error(w, `estado debe ser "on" u "off"`, http.StatusBadRequest)
+			return
+		}
+
+		if id, ok := proximoPendiente(prefijo); ok {
+			scheduler.Cancel(id)
+			if hora, err := time.Parse(time.RFC3339, strings.TrimPrefix(id, prefijo)); err == nil {
+				EliminarEvento(hora, peticion.Estado)
+			}
+		}
+
+		ahora := time.Now()
+		if err := conmutarEnchufe(r.Context(), driver, peticion.Estado, ahora, ahora, 0); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
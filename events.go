@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventoProgramado representa un encendido o apagado planificado, usado por
+// el endpoint GET /next del servidor de administración.
+type EventoProgramado struct {
+	Hora   time.Time `json:"hora"`
+	Estado string    `json:"estado"` // "on" u "off"
+}
+
+var (
+	eventosMu          sync.Mutex
+	eventosProgramados []EventoProgramado
+)
+
+// RegistrarEvento añade un evento planificado a la lista de próximos eventos.
+func RegistrarEvento(hora time.Time, estado string) {
+	eventosMu.Lock()
+	defer eventosMu.Unlock()
+	eventosProgramados = append(eventosProgramados, EventoProgramado{Hora: hora, Estado: estado})
+}
+
+// ReiniciarEventos vacía la lista de eventos planificados, usado cuando se
+// cancela una planificación anterior para sustituirla por una nueva.
+func ReiniciarEventos() {
+	eventosMu.Lock()
+	defer eventosMu.Unlock()
+	eventosProgramados = nil
+}
+
+// EliminarEvento quita de la lista el evento con la hora y el estado dados,
+// usado cuando un override manual deja obsoleto un único encendido o apagado
+// planificado sin reiniciar toda la lista (que seguiría teniendo el resto de
+// eventos vigentes).
+func EliminarEvento(hora time.Time, estado string) {
+	eventosMu.Lock()
+	defer eventosMu.Unlock()
+
+	for i, evento := range eventosProgramados {
+		if evento.Hora.Equal(hora) && evento.Estado == estado {
+			eventosProgramados = append(eventosProgramados[:i], eventosProgramados[i+1:]...)
+			return
+		}
+	}
+}
+
+// ProximosEventos devuelve los eventos planificados que todavía no han
+// ocurrido, ordenados por hora ascendente.
+func ProximosEventos() []EventoProgramado {
+	eventosMu.Lock()
+	defer eventosMu.Unlock()
+
+	ahora := time.Now()
+	var proximos []EventoProgramado
+	for _, evento := range eventosProgramados {
+		if evento.Hora.After(ahora) {
+			proximos = append(proximos, evento)
+		}
+	}
+	sort.Slice(proximos, func(i, j int) bool {
+		return proximos[i].Hora.Before(proximos[j].Hora)
+	})
+	return proximos
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEliminarEventoQuitaSoloElIndicado(t *testing.T) {
+	ReiniciarEventos()
+	defer ReiniciarEventos()
+
+	encendido := time.Now().Add(time.Hour)
+	apagado := time.Now().Add(2 * time.Hour)
+	RegistrarEvento(encendido, "on")
+	RegistrarEvento(apagado, "off")
+
+	EliminarEvento(encendido, "on")
+
+	proximos := ProximosEventos()
+	if len(proximos) != 1 {
+		t.Fatalf("se esperaba 1 evento tras eliminar el encendido, se obtuvieron %d", len(proximos))
+	}
+	if proximos[0].Estado != "off" {
+		t.Errorf("el evento restante debería ser 'off', fue %q", proximos[0].Estado)
+	}
+}
+
+func TestEliminarEventoSinCoincidenciaNoCambiaNada(t *testing.T) {
+	ReiniciarEventos()
+	defer ReiniciarEventos()
+
+	hora := time.Now().Add(time.Hour)
+	RegistrarEvento(hora, "on")
+
+	EliminarEvento(hora, "off")
+
+	if len(ProximosEventos()) != 1 {
+		t.Error("eliminar un evento que no coincide no debería afectar a la lista")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// EventoLog es la forma común de los eventos de negocio que se registran
+// como JSON estructurado (en lugar de texto libre en español), para que se
+// puedan indexar y filtrar en un agregador de logs.
+type EventoLog struct {
+	Event     string  `json:"event"`
+	HourStart string  `json:"hour_start,omitempty"`
+	HourEnd   string  `json:"hour_end,omitempty"`
+	Price     float64 `json:"price,omitempty"`
+	Driver    string  `json:"driver,omitempty"`
+	Err       string  `json:"err,omitempty"`
+}
+
+// logEvento serializa evento a JSON y lo escribe con el logger estándar.
+func logEvento(evento EventoLog) {
+	datos, err := json.Marshal(evento)
+	if err != nil {
+		log.Printf(`{"event":"log_marshal_error","err":%q}`, err.Error())
+		return
+	}
+	log.Println(string(datos))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Métricas Prometheus expuestas en /metrics, para poder vigilar el programador
+// desde un stack Prometheus/Grafana de home-lab y alertar si la API de
+// precios falla o el enchufe no conmuta.
+var (
+	plugSwitchTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "plug_switch_total",
+		Help: "Número de intentos de conmutación del enchufe, por estado y resultado.",
+	}, []string{"state", "result"})
+
+	plugStateGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "plug_state",
+		Help: "Estado actual del enchufe: 1 encendido, 0 apagado.",
+	})
+
+	plannedWindowPrice = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "planned_window_price_eur_per_kwh",
+		Help: "Precio de la última ventana de encendido planificada.",
+	})
+
+	priceAPIRequestSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "price_api_request_seconds",
+		Help: "Duración de las solicitudes a la API de precios de luz.",
+	})
+
+	nextSwitchTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "next_switch_timestamp_seconds",
+		Help: "Marca de tiempo Unix del próximo encendido/apagado planificado, por estado.",
+	}, []string{"state"})
+)
+
+// registrarConmutacion actualiza las métricas tras un intento de encender o
+// apagar el enchufe.
+func registrarConmutacion(estado string, err error) {
+	resultado := "ok"
+	if err != nil {
+		resultado = "err"
+	}
+	plugSwitchTotal.WithLabelValues(estado, resultado).Inc()
+
+	if err == nil {
+		if estado == "on" {
+			plugStateGauge.Set(1)
+		} else {
+			plugStateGauge.Set(0)
+		}
+	}
+}
+
+// registrarProximaConmutacion expone en una gauge la hora del próximo
+// encendido o apagado planificado.
+func registrarProximaConmutacion(estado string, cuando time.Time) {
+	nextSwitchTimestamp.WithLabelValues(estado).Set(float64(cuando.Unix()))
+}
+
+// IniciarServidorMetrics levanta un servidor HTTP que expone únicamente
+// GET /metrics, independiente del servidor de administración: un despliegue
+// puede así habilitar Prometheus sin exponer la API de /schedule y /override.
+func IniciarServidorMetrics(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		logEvento(EventoLog{Event: "metrics_server_started"})
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logEvento(EventoLog{Event: "metrics_server_error", Err: err.Error()})
+		}
+	}()
+	return srv
+}
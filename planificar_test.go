@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// preciosDelDia construye un PreciosLuz con 24 horas consecutivas en formato
+// "hh-hh" a partir de precio, sobreescribiendo con precios los índices
+// indicados en baratas.
+func preciosDelDia(precio float64, baratas map[int]float64) PreciosLuz {
+	precios := make(PreciosLuz, 24)
+	for h := 0; h < 24; h++ {
+		p := precio
+		if especial, ok := baratas[h]; ok {
+			p = especial
+		}
+		hour := fmt.Sprintf("%02d-%02d", h, (h+1)%24)
+		precios[hour] = PrecioLuz{Date: "2024-01-01", Hour: hour, Price: p}
+	}
+	return precios
+}
+
+func TestPlanificarVentanasEligeElPrecioMasBarato(t *testing.T) {
+	// Todas las horas a 1.0 salvo las 10 y 11, mucho más baratas.
+	precios := preciosDelDia(1.0, map[int]float64{10: 0.1, 11: 0.1})
+	opts := PlanOpts{SlotDuration: 2 * time.Hour, MaxDailyOn: 2 * time.Hour}
+
+	ventanas := PlanificarVentanas(precios, opts, nil, nil)
+	if len(ventanas) != 1 {
+		t.Fatalf("se esperaba 1 ventana, se obtuvieron %d", len(ventanas))
+	}
+	if ventanas[0].HoraInicio != "10-11" {
+		t.Errorf("HoraInicio = %q, se esperaba %q", ventanas[0].HoraInicio, "10-11")
+	}
+	if ventanas[0].Duracion != 2*time.Hour {
+		t.Errorf("Duracion = %v, se esperaba %v", ventanas[0].Duracion, 2*time.Hour)
+	}
+}
+
+func TestPlanificarVentanasMaximizaNumeroDeVentanas(t *testing.T) {
+	precios := preciosDelDia(1.0, nil)
+	// Presupuesto para 2 ventanas de 1h: debe usarlas ambas aunque el precio
+	// sea el mismo en todas las horas, en vez de dejar presupuesto sin usar.
+	opts := PlanOpts{SlotDuration: time.Hour, MaxDailyOn: 2 * time.Hour}
+
+	ventanas := PlanificarVentanas(precios, opts, nil, nil)
+	if len(ventanas) != 2 {
+		t.Fatalf("se esperaba 2 ventanas, se obtuvieron %d", len(ventanas))
+	}
+}
+
+func TestPlanificarVentanasRespetaMinGapBetween(t *testing.T) {
+	precios := preciosDelDia(1.0, nil)
+	opts := PlanOpts{SlotDuration: time.Hour, MaxDailyOn: 2 * time.Hour, MinGapBetween: 3 * time.Hour}
+
+	ventanas := PlanificarVentanas(precios, opts, nil, nil)
+	if len(ventanas) < 2 {
+		t.Skip("no se planificaron suficientes ventanas para comprobar el hueco")
+	}
+	primeraHora := parseInicioHora(t, ventanas[0].HoraInicio)
+	segundaHora := parseInicioHora(t, ventanas[1].HoraInicio)
+	if segundaHora-primeraHora < 1+3 {
+		t.Errorf("las ventanas %v y %v no respetan MinGapBetween=3h", ventanas[0], ventanas[1])
+	}
+}
+
+func TestPlanificarVentanasExcluyeHorasDeny(t *testing.T) {
+	precios := preciosDelDia(1.0, map[int]float64{5: 0.01})
+	sched := NewSchedule()
+	sched.Set([]EntradaSchedule{{Start: "05:00", End: "06:00", Mode: Deny}})
+	opts := PlanOpts{SlotDuration: time.Hour, MaxDailyOn: time.Hour}
+
+	ventanas := PlanificarVentanas(precios, opts, nil, sched)
+	for _, v := range ventanas {
+		if v.HoraInicio == "05-06" {
+			t.Errorf("la hora 05-06 está en modo Deny y no debería haberse planificado: %v", ventanas)
+		}
+	}
+}
+
+func TestPlanificarVentanasIncluyeHorasForzadasConSuPropiaDuracion(t *testing.T) {
+	precios := preciosDelDia(1.0, nil)
+	sched := NewSchedule()
+	sched.Set([]EntradaSchedule{{Start: "10:00", End: "12:00", Mode: Force}})
+	// Presupuesto 0: sin Force no se planificaría ninguna ventana por precio.
+	opts := PlanOpts{SlotDuration: time.Hour, MaxDailyOn: 0}
+
+	ventanas := PlanificarVentanas(precios, opts, nil, sched)
+	if len(ventanas) != 1 {
+		t.Fatalf("se esperaba 1 ventana forzada, se obtuvieron %d: %v", len(ventanas), ventanas)
+	}
+	if ventanas[0].Duracion != 2*time.Hour {
+		t.Errorf("Duracion de la ventana forzada = %v, se esperaba %v", ventanas[0].Duracion, 2*time.Hour)
+	}
+}
+
+func parseInicioHora(t *testing.T, horaRango string) int {
+	t.Helper()
+	var inicio, fin int
+	if _, err := fmt.Sscanf(horaRango, "%d-%d", &inicio, &fin); err != nil {
+		t.Fatalf("no se pudo parsear el rango horario %q: %v", horaRango, err)
+	}
+	return inicio
+}
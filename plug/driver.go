@@ -0,0 +1,20 @@
+// Package plug abstrae el enchufe inteligente detrás de la interfaz Driver,
+// para que el programador pueda encender y apagar distintos modelos de
+// enchufe sin conocer su protocolo concreto. Todas las operaciones aceptan un
+// context.Context para que una nueva planificación o un SIGTERM puedan
+// abortar una petición en curso en lugar de esperar a que termine.
+package plug
+
+import "context"
+
+// Driver es la interfaz que deben implementar todos los enchufes soportados.
+type Driver interface {
+	// On enciende el enchufe.
+	On(ctx context.Context) error
+	// Off apaga el enchufe.
+	Off(ctx context.Context) error
+	// State devuelve true si el enchufe está encendido.
+	State(ctx context.Context) (bool, error)
+	// Name identifica el driver (p. ej. "http", "tasmota") para logs y métricas.
+	Name() string
+}
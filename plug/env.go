@@ -0,0 +1,77 @@
+package plug
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewDriverFromEnv construye el Driver seleccionado por la variable de
+// entorno ENCHUFE_DRIVER ("http", "tasmota", "shelly", "tuya" o "mqtt"), leyendo
+// la configuración específica de cada driver de sus propias variables de
+// entorno. Por compatibilidad, un ENCHUFE_DRIVER vacío se trata como "http".
+func NewDriverFromEnv() (Driver, error) {
+	driver := os.Getenv("ENCHUFE_DRIVER")
+	if driver == "" {
+		driver = "http"
+	}
+
+	switch driver {
+	case "http":
+		url := os.Getenv("ENCHUFE_API")
+		if url == "" {
+			return nil, fmt.Errorf("ENCHUFE_API debe estar definida para ENCHUFE_DRIVER=http")
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("ENCHUFE_API debe empezar con 'http://' o 'https://'")
+		}
+		return NewHTTPDriver(url), nil
+
+	case "tasmota":
+		url := os.Getenv("TASMOTA_URL")
+		if url == "" {
+			return nil, fmt.Errorf("TASMOTA_URL debe estar definida para ENCHUFE_DRIVER=tasmota")
+		}
+		return NewTasmotaDriver(url), nil
+
+	case "shelly":
+		url := os.Getenv("SHELLY_URL")
+		if url == "" {
+			return nil, fmt.Errorf("SHELLY_URL debe estar definida para ENCHUFE_DRIVER=shelly")
+		}
+		switchID := 0
+		if v := os.Getenv("SHELLY_SWITCH_ID"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("SHELLY_SWITCH_ID inválido: %w", err)
+			}
+			switchID = parsed
+		}
+		return NewShellyDriver(url, switchID), nil
+
+	case "tuya":
+		bridgeURL := os.Getenv("TUYA_BRIDGE_URL")
+		deviceID := os.Getenv("TUYA_DEVICE_ID")
+		localKey := os.Getenv("TUYA_LOCAL_KEY")
+		if bridgeURL == "" || deviceID == "" {
+			return nil, fmt.Errorf("TUYA_BRIDGE_URL y TUYA_DEVICE_ID deben estar definidas para ENCHUFE_DRIVER=tuya")
+		}
+		return NewTuyaDriver(bridgeURL, deviceID, localKey), nil
+
+	case "mqtt":
+		broker := os.Getenv("MQTT_BROKER")
+		topic := os.Getenv("MQTT_TOPIC")
+		if broker == "" || topic == "" {
+			return nil, fmt.Errorf("MQTT_BROKER y MQTT_TOPIC deben estar definidas para ENCHUFE_DRIVER=mqtt")
+		}
+		clientID := os.Getenv("MQTT_CLIENT_ID")
+		if clientID == "" {
+			clientID = "smart-plug-scheduler"
+		}
+		return NewMQTTDriver(broker, topic, clientID)
+
+	default:
+		return nil, fmt.Errorf("ENCHUFE_DRIVER desconocido: %q", driver)
+	}
+}
@@ -0,0 +1,54 @@
+package plug
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// HTTPDriver controla el enchufe a través de la API HTTP original del
+// proyecto: POST {URL}/encender y POST {URL}/apagar. Esta API no expone un
+// endpoint de estado, así que State siempre devuelve un error.
+type HTTPDriver struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPDriver crea un HTTPDriver para la URL dada.
+func NewHTTPDriver(url string) *HTTPDriver {
+	return &HTTPDriver{URL: url, Client: &http.Client{}}
+}
+
+func (d *HTTPDriver) On(ctx context.Context) error {
+	return d.post(ctx, "/encender")
+}
+
+func (d *HTTPDriver) Off(ctx context.Context) error {
+	return d.post(ctx, "/apagar")
+}
+
+func (d *HTTPDriver) State(ctx context.Context) (bool, error) {
+	return false, fmt.Errorf("el driver http no soporta consultar el estado del enchufe")
+}
+
+func (d *HTTPDriver) Name() string {
+	return "http"
+}
+
+func (d *HTTPDriver) post(ctx context.Context, ruta string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL+ruta, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error al llamar a %s: %w", ruta, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error al llamar a %s: status code %d", ruta, resp.StatusCode)
+	}
+	return nil
+}
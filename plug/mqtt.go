@@ -0,0 +1,58 @@
+package plug
+
+import (
+	"context"
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTDriver controla el enchufe publicando mensajes retenidos ("ON"/"OFF")
+// en Topic, tal y como esperan los firmwares basados en MQTT (ESPHome,
+// Tasmota en modo MQTT, etc.).
+type MQTTDriver struct {
+	Client mqtt.Client
+	Topic  string
+}
+
+// NewMQTTDriver conecta un cliente MQTT al broker dado y devuelve un
+// MQTTDriver que publica en topic.
+func NewMQTTDriver(broker, topic, clientID string) (*MQTTDriver, error) {
+	opts := mqtt.NewClientOptions().AddBroker(broker).SetClientID(clientID)
+	client := mqtt.NewClient(opts)
+
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("error al conectar con el broker MQTT: %w", token.Error())
+	}
+
+	return &MQTTDriver{Client: client, Topic: topic}, nil
+}
+
+func (d *MQTTDriver) On(ctx context.Context) error {
+	return d.publicar(ctx, "ON")
+}
+
+func (d *MQTTDriver) Off(ctx context.Context) error {
+	return d.publicar(ctx, "OFF")
+}
+
+func (d *MQTTDriver) State(ctx context.Context) (bool, error) {
+	return false, fmt.Errorf("el driver mqtt no soporta consultar el estado del enchufe")
+}
+
+func (d *MQTTDriver) Name() string {
+	return "mqtt"
+}
+
+// publicar envía payload como mensaje retenido en Topic, abortando si ctx se
+// cancela antes de que el broker confirme la publicación.
+func (d *MQTTDriver) publicar(ctx context.Context, payload string) error {
+	token := d.Client.Publish(d.Topic, 1, true, payload)
+
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
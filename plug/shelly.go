@@ -0,0 +1,83 @@
+package plug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ShellyDriver controla un enchufe Shelly Gen2+ a través de su API RPC
+// ({URL}/rpc/Switch.Set y {URL}/rpc/Switch.GetStatus). URL se usa tal cual,
+// con su esquema (p. ej. "http://").
+type ShellyDriver struct {
+	URL      string
+	SwitchID int
+	Client   *http.Client
+}
+
+// NewShellyDriver crea un ShellyDriver para la URL base del dispositivo y el
+// id del relé a controlar (0 en la mayoría de enchufes de un solo canal).
+func NewShellyDriver(baseURL string, switchID int) *ShellyDriver {
+	return &ShellyDriver{URL: baseURL, SwitchID: switchID, Client: &http.Client{}}
+}
+
+func (d *ShellyDriver) On(ctx context.Context) error {
+	return d.set(ctx, true)
+}
+
+func (d *ShellyDriver) Off(ctx context.Context) error {
+	return d.set(ctx, false)
+}
+
+func (d *ShellyDriver) State(ctx context.Context) (bool, error) {
+	body, err := d.rpc(ctx, "Switch.GetStatus", map[string]any{"id": d.SwitchID})
+	if err != nil {
+		return false, err
+	}
+
+	var respuesta struct {
+		Output bool `json:"output"`
+	}
+	if err := json.Unmarshal(body, &respuesta); err != nil {
+		return false, fmt.Errorf("respuesta de Shelly inesperada: %w", err)
+	}
+	return respuesta.Output, nil
+}
+
+func (d *ShellyDriver) Name() string {
+	return "shelly"
+}
+
+func (d *ShellyDriver) set(ctx context.Context, encendido bool) error {
+	_, err := d.rpc(ctx, "Switch.Set", map[string]any{"id": d.SwitchID, "on": encendido})
+	return err
+}
+
+func (d *ShellyDriver) rpc(ctx context.Context, metodo string, params map[string]any) ([]byte, error) {
+	cuerpo, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/rpc/%s", d.URL, metodo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(cuerpo))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error al llamar a %s: %w", metodo, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error al llamar a %s: status code %d", metodo, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,71 @@
+package plug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// TasmotaDriver controla un enchufe con firmware Tasmota a través de su API
+// de comandos HTTP ({URL}/cm?cmnd=...). URL se usa tal cual, con su esquema
+// (p. ej. "http://").
+type TasmotaDriver struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewTasmotaDriver crea un TasmotaDriver para la URL base del dispositivo.
+func NewTasmotaDriver(baseURL string) *TasmotaDriver {
+	return &TasmotaDriver{URL: baseURL, Client: &http.Client{}}
+}
+
+func (d *TasmotaDriver) On(ctx context.Context) error {
+	_, err := d.comando(ctx, "Power On")
+	return err
+}
+
+func (d *TasmotaDriver) Off(ctx context.Context) error {
+	_, err := d.comando(ctx, "Power Off")
+	return err
+}
+
+func (d *TasmotaDriver) State(ctx context.Context) (bool, error) {
+	var respuesta struct {
+		Power string `json:"POWER"`
+	}
+	body, err := d.comando(ctx, "Power")
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(body, &respuesta); err != nil {
+		return false, fmt.Errorf("respuesta de Tasmota inesperada: %w", err)
+	}
+	return respuesta.Power == "ON", nil
+}
+
+func (d *TasmotaDriver) Name() string {
+	return "tasmota"
+}
+
+func (d *TasmotaDriver) comando(ctx context.Context, cmnd string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/cm?cmnd=%s", d.URL, url.QueryEscape(cmnd))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error al enviar el comando %q a Tasmota: %w", cmnd, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error al enviar el comando %q a Tasmota: status code %d", cmnd, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,90 @@
+package plug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// TuyaDriver controla un enchufe Tuya a través de un puente HTTP local (por
+// ejemplo tinytuya en modo servidor) que traduce peticiones JSON sencillas al
+// protocolo propietario cifrado de Tuya. BridgeURL apunta a ese puente,
+// DeviceID y LocalKey identifican el dispositivo concreto dentro de él.
+type TuyaDriver struct {
+	BridgeURL string
+	DeviceID  string
+	LocalKey  string
+	Client    *http.Client
+}
+
+// NewTuyaDriver crea un TuyaDriver para el dispositivo DeviceID, accedido a
+// través del puente en bridgeURL.
+func NewTuyaDriver(bridgeURL, deviceID, localKey string) *TuyaDriver {
+	return &TuyaDriver{BridgeURL: bridgeURL, DeviceID: deviceID, LocalKey: localKey, Client: &http.Client{}}
+}
+
+func (d *TuyaDriver) On(ctx context.Context) error {
+	return d.setSwitch(ctx, true)
+}
+
+func (d *TuyaDriver) Off(ctx context.Context) error {
+	return d.setSwitch(ctx, false)
+}
+
+func (d *TuyaDriver) State(ctx context.Context) (bool, error) {
+	body, err := d.peticion(ctx, http.MethodGet, "/devices/"+d.DeviceID, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var respuesta struct {
+		DPS map[string]bool `json:"dps"`
+	}
+	if err := json.Unmarshal(body, &respuesta); err != nil {
+		return false, fmt.Errorf("respuesta del puente Tuya inesperada: %w", err)
+	}
+	// El interruptor principal de los enchufes Tuya se publica como el punto de datos "1".
+	return respuesta.DPS["1"], nil
+}
+
+func (d *TuyaDriver) Name() string {
+	return "tuya"
+}
+
+func (d *TuyaDriver) setSwitch(ctx context.Context, encendido bool) error {
+	cuerpo, err := json.Marshal(map[string]any{"dps": map[string]bool{"1": encendido}})
+	if err != nil {
+		return err
+	}
+	_, err = d.peticion(ctx, http.MethodPost, "/devices/"+d.DeviceID, cuerpo)
+	return err
+}
+
+func (d *TuyaDriver) peticion(ctx context.Context, metodo, ruta string, cuerpo []byte) ([]byte, error) {
+	var lector io.Reader
+	if cuerpo != nil {
+		lector = bytes.NewReader(cuerpo)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, metodo, d.BridgeURL+ruta, lector)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Local-Key", d.LocalKey)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error al llamar al puente Tuya: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error al llamar al puente Tuya: status code %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ruboyi/smart-plug-scheduler/tariff"
+)
+
+// Modo indica cómo debe tratarse una franja horaria al planificar ventanas.
+type Modo int
+
+const (
+	// Allow permite que la franja compita normalmente por precio (comportamiento por defecto).
+	Allow Modo = iota
+	// Deny prohíbe encender el enchufe durante la franja, sin importar el precio.
+	Deny
+	// Force obliga a encender el enchufe durante toda la franja, sin importar el precio.
+	Force
+)
+
+// MarshalJSON representa Modo como "allow", "deny" o "force" en lugar de un entero.
+func (m Modo) MarshalJSON() ([]byte, error) {
+	switch m {
+	case Allow:
+		return json.Marshal("allow")
+	case Deny:
+		return json.Marshal("deny")
+	case Force:
+		return json.Marshal("force")
+	default:
+		return nil, fmt.Errorf("modo desconocido: %d", m)
+	}
+}
+
+// UnmarshalJSON acepta "allow", "deny" o "force".
+func (m *Modo) UnmarshalJSON(data []byte) error {
+	var texto string
+	if err := json.Unmarshal(data, &texto); err != nil {
+		return err
+	}
+	switch texto {
+	case "allow":
+		*m = Allow
+	case "deny":
+		*m = Deny
+	case "force":
+		*m = Force
+	default:
+		return fmt.Errorf("modo desconocido: %q", texto)
+	}
+	return nil
+}
+
+// EntradaSchedule define una regla aplicada a un conjunto de días de la semana
+// durante el rango horario [Start, End).
+type EntradaSchedule struct {
+	Weekdays []time.Weekday `json:"weekdays"`
+	Start    string         `json:"start"` // "HH:MM"
+	End      string         `json:"end"`   // "HH:MM"
+	Mode     Modo           `json:"mode"`
+}
+
+// Schedule es la política de horarios permitidos, prohibidos y forzados que
+// PlanificarVentanas respeta al elegir franjas. Es segura para uso concurrente
+// porque el servidor de administración puede modificarla en caliente.
+type Schedule struct {
+	mu       sync.RWMutex
+	entradas []EntradaSchedule
+}
+
+// NewSchedule crea un Schedule vacío (todas las franjas en modo Allow).
+func NewSchedule() *Schedule {
+	return &Schedule{}
+}
+
+// Set reemplaza atómicamente todas las entradas del Schedule.
+func (s *Schedule) Set(entradas []EntradaSchedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entradas = entradas
+}
+
+// Entries devuelve una copia de las entradas actuales del Schedule.
+func (s *Schedule) Entries() []EntradaSchedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	copia := make([]EntradaSchedule, len(s.entradas))
+	copy(copia, s.entradas)
+	return copia
+}
+
+// Evaluate devuelve el Modo aplicable a hora t: Force tiene prioridad sobre
+// Deny, que a su vez tiene prioridad sobre Allow. Si ninguna entrada coincide,
+// el resultado es Allow.
+func (s *Schedule) Evaluate(t time.Time) Modo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resultado := Allow
+	for _, entrada := range s.entradas {
+		if !entrada.cubre(t) {
+			continue
+		}
+		if entrada.Mode == Force {
+			return Force
+		}
+		if entrada.Mode == Deny {
+			resultado = Deny
+		}
+	}
+	return resultado
+}
+
+// cubre indica si t cae dentro de esta entrada, teniendo en cuenta el día de
+// la semana y franjas que cruzan la medianoche (Start > End).
+func (e EntradaSchedule) cubre(t time.Time) bool {
+	if len(e.Weekdays) > 0 {
+		encontrado := false
+		for _, dia := range e.Weekdays {
+			if dia == t.Weekday() {
+				encontrado = true
+				break
+			}
+		}
+		if !encontrado {
+			return false
+		}
+	}
+
+	actual := t.Format("15:04")
+	if e.Start <= e.End {
+		return actual >= e.Start && actual < e.End
+	}
+	return actual >= e.Start || actual < e.End
+}
+
+// ventanasForzadas agrupa en ventanas las horas consecutivas que el Schedule
+// marca como Force, independientemente del precio o del presupuesto diario.
+func ventanasForzadas(precios PreciosLuz, tarifa *tariff.Embed, sched *Schedule) []Ventana {
+	var listaPrecios []PrecioLuz
+	for _, precio := range precios {
+		listaPrecios = append(listaPrecios, precio)
+	}
+	sort.Slice(listaPrecios, func(i, j int) bool {
+		return listaPrecios[i].Hour < listaPrecios[j].Hour
+	})
+
+	var ventanas []Ventana
+	var actual *Ventana
+	for _, precio := range listaPrecios {
+		if sched.Evaluate(horaPrecio(precio)) != Force {
+			actual = nil
+			continue
+		}
+		if actual == nil {
+			ventanas = append(ventanas, Ventana{HoraInicio: precio.Hour, HoraFin: precio.Hour})
+			actual = &ventanas[len(ventanas)-1]
+		} else {
+			actual.HoraFin = precio.Hour
+		}
+		actual.Duracion += time.Hour
+		actual.PrecioTotal += precioEfectivo(precio, tarifa)
+	}
+	return ventanas
+}
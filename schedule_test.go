@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntradaScheduleCubreFranjaNormal(t *testing.T) {
+	entrada := EntradaSchedule{Start: "10:00", End: "14:00"}
+
+	// 2024-01-01 es lunes.
+	if !entrada.cubre(hora(t, "2024-01-01T10:00:00")) {
+		t.Error("la hora de inicio debería estar cubierta")
+	}
+	if entrada.cubre(hora(t, "2024-01-01T14:00:00")) {
+		t.Error("la hora de fin es exclusiva y no debería estar cubierta")
+	}
+}
+
+func TestEntradaScheduleCubreCruzaMedianoche(t *testing.T) {
+	entrada := EntradaSchedule{Start: "23:00", End: "07:00"}
+
+	if !entrada.cubre(hora(t, "2024-01-01T23:30:00")) {
+		t.Error("debería cubrir una hora posterior al inicio, antes de la medianoche")
+	}
+	if !entrada.cubre(hora(t, "2024-01-02T06:00:00")) {
+		t.Error("debería cubrir una hora antes del fin, ya en el día siguiente")
+	}
+	if entrada.cubre(hora(t, "2024-01-01T12:00:00")) {
+		t.Error("el mediodía no debería estar cubierto por una franja nocturna")
+	}
+}
+
+func TestEntradaScheduleCubreFiltraPorDiaDeLaSemana(t *testing.T) {
+	entrada := EntradaSchedule{Weekdays: []time.Weekday{time.Saturday, time.Sunday}, Start: "00:00", End: "23:59"}
+
+	if entrada.cubre(hora(t, "2024-01-01T12:00:00")) {
+		t.Error("un lunes no debería estar cubierto por una entrada de fin de semana")
+	}
+	if !entrada.cubre(hora(t, "2024-01-06T12:00:00")) {
+		t.Error("un sábado sí debería estar cubierto")
+	}
+}
+
+func TestScheduleEvaluatePrioridades(t *testing.T) {
+	sched := NewSchedule()
+	sched.Set([]EntradaSchedule{
+		{Start: "00:00", End: "23:59", Mode: Deny},
+		{Start: "10:00", End: "11:00", Mode: Force},
+	})
+
+	if modo := sched.Evaluate(hora(t, "2024-01-01T05:00:00")); modo != Deny {
+		t.Errorf("fuera de la franja Force, esperaba Deny, obtuve %v", modo)
+	}
+	if modo := sched.Evaluate(hora(t, "2024-01-01T10:30:00")); modo != Force {
+		t.Errorf("dentro de la franja Force, esperaba Force aunque también aplique Deny, obtuve %v", modo)
+	}
+}
+
+func TestScheduleEvaluateSinEntradasEsAllow(t *testing.T) {
+	sched := NewSchedule()
+	if modo := sched.Evaluate(hora(t, "2024-01-01T10:00:00")); modo != Allow {
+		t.Errorf("un Schedule vacío debería evaluar siempre a Allow, obtuve %v", modo)
+	}
+}
+
+// hora parsea una fecha y hora sin zona horaria, que time.Parse interpreta
+// como UTC, para no depender de la zona horaria del entorno donde se ejecutan
+// los tests.
+func hora(t *testing.T, valor string) time.Time {
+	t.Helper()
+	instante, err := time.Parse("2006-01-02T15:04:05", valor)
+	if err != nil {
+		t.Fatalf("no se pudo parsear %q: %v", valor, err)
+	}
+	return instante
+}
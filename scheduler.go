@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AccionProgramada es el trabajo que ejecuta el Scheduler al vencer un
+// temporizador. El ctx recibido se cancela si la acción se cancela o se
+// reprograma mientras está en curso.
+type AccionProgramada func(ctx context.Context)
+
+// entradaProgramada agrupa, igual que hace net con sus temporizadores de
+// deadline, el *time.Timer de una acción pendiente junto con el canal que
+// permite cancelarla antes de que dispare.
+type entradaProgramada struct {
+	temporizador *time.Timer
+	cancelCh     chan struct{}
+	accion       AccionProgramada
+}
+
+// Scheduler sustituye a los goroutines "dispara y olvida" basados en
+// time.Sleep por temporizadores identificados por id: programar de nuevo el
+// mismo id detiene y reemplaza limpiamente el temporizador anterior en lugar
+// de dejarlo corriendo en segundo plano.
+type Scheduler struct {
+	mu          sync.Mutex
+	pendientes  map[string]*entradaProgramada
+	enEjecucion sync.WaitGroup
+}
+
+// NewScheduler crea un Scheduler sin temporizadores pendientes.
+func NewScheduler() *Scheduler {
+	return &Scheduler{pendientes: make(map[string]*entradaProgramada)}
+}
+
+// Set detiene cualquier temporizador previo con el mismo id y programa
+// accion para que se ejecute en cuando.
+func (s *Scheduler) Set(id string, cuando time.Time, accion AccionProgramada) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancelar(id)
+
+	cancelCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	ctx, cancelCtx := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-cancelCh:
+			cancelCtx()
+		case <-doneCh:
+			cancelCtx()
+		}
+	}()
+
+	s.enEjecucion.Add(1)
+	temporizador := time.AfterFunc(time.Until(cuando), func() {
+		defer close(doneCh)
+		defer s.enEjecucion.Done()
+
+		select {
+		case <-cancelCh:
+			return
+		default:
+		}
+		accion(ctx)
+
+		// Limpiar pendientes tras disparar con normalidad: cancelar ya lo hace
+		// en el camino de cancelación, pero aquí nadie más lo hace, y como cada
+		// ProgramarEncendido usa un id nuevo (con la hora incluida), sin este
+		// borrado el mapa crecería sin límite con cada ventana programada.
+		// Solo se borra si la entrada sigue siendo la nuestra: si Set ya la
+		// reemplazó (reprogramando el mismo id) entretanto, cancelar ya se
+		// habrá encargado de la entrada antigua y esta comprobación evita
+		// borrar la nueva por error.
+		s.mu.Lock()
+		if actual, ok := s.pendientes[id]; ok && actual.cancelCh == cancelCh {
+			delete(s.pendientes, id)
+		}
+		s.mu.Unlock()
+	})
+
+	s.pendientes[id] = &entradaProgramada{
+		temporizador: temporizador,
+		cancelCh:     cancelCh,
+		accion:       accion,
+	}
+}
+
+// Cancel detiene el temporizador identificado por id, si existe, cancelando
+// también el contexto de la acción si ya estaba en curso.
+func (s *Scheduler) Cancel(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelar(id)
+}
+
+// cancelar detiene y elimina el temporizador id. Debe llamarse con s.mu tomado.
+func (s *Scheduler) cancelar(id string) {
+	entrada, ok := s.pendientes[id]
+	if !ok {
+		return
+	}
+	delete(s.pendientes, id)
+	close(entrada.cancelCh)
+	if entrada.temporizador.Stop() {
+		// El temporizador no había disparado todavía: su AfterFunc nunca se
+		// ejecutará, así que liberamos aquí la cuenta que le correspondía.
+		s.enEjecucion.Done()
+	}
+}
+
+// Reschedule mueve la acción ya registrada con id a nuevaHora, reutilizando
+// la misma acción pasada a Set. Devuelve error si no hay ninguna acción
+// pendiente con ese id.
+func (s *Scheduler) Reschedule(id string, nuevaHora time.Time) error {
+	s.mu.Lock()
+	entrada, ok := s.pendientes[id]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("no hay ninguna acción programada con id %q", id)
+	}
+	accion := entrada.accion
+	s.mu.Unlock()
+
+	s.Set(id, nuevaHora, accion)
+	return nil
+}
+
+// Shutdown detiene los temporizadores que aún no han disparado y espera a que
+// todas las acciones en curso terminen, o a que ctx expire, lo que ocurra antes.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for id, entrada := range s.pendientes {
+		if entrada.temporizador.Stop() {
+			// Igual que en cancelar: su AfterFunc nunca se ejecutará.
+			delete(s.pendientes, id)
+			close(entrada.cancelCh)
+			s.enEjecucion.Done()
+		}
+	}
+	s.mu.Unlock()
+
+	listo := make(chan struct{})
+	go func() {
+		s.enEjecucion.Wait()
+		close(listo)
+	}()
+
+	select {
+	case <-listo:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSetEjecutaLaAccion(t *testing.T) {
+	s := NewScheduler()
+	disparado := make(chan struct{})
+
+	s.Set("evento", time.Now().Add(10*time.Millisecond), func(ctx context.Context) {
+		close(disparado)
+	})
+
+	select {
+	case <-disparado:
+	case <-time.After(time.Second):
+		t.Fatal("la acción no se ejecutó a tiempo")
+	}
+}
+
+func TestSchedulerSetLimpiaPendientesTrasDispararse(t *testing.T) {
+	s := NewScheduler()
+	disparado := make(chan struct{})
+
+	s.Set("evento", time.Now().Add(10*time.Millisecond), func(ctx context.Context) {
+		close(disparado)
+	})
+
+	select {
+	case <-disparado:
+	case <-time.After(time.Second):
+		t.Fatal("la acción no se ejecutó a tiempo")
+	}
+
+	// Dar tiempo a que el AfterFunc termine de limpiar tras cerrar disparado.
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	_, pendiente := s.pendientes["evento"]
+	s.mu.Unlock()
+	if pendiente {
+		t.Error("el id debería haberse eliminado de pendientes tras dispararse, no solo al cancelar")
+	}
+}
+
+func TestSchedulerCancelEvitaLaAccion(t *testing.T) {
+	s := NewScheduler()
+	disparado := make(chan struct{})
+
+	s.Set("evento", time.Now().Add(50*time.Millisecond), func(ctx context.Context) {
+		close(disparado)
+	})
+	s.Cancel("evento")
+
+	select {
+	case <-disparado:
+		t.Fatal("la acción no debería haberse ejecutado tras Cancel")
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestSchedulerSetConMismoIdReemplazaElAnterior(t *testing.T) {
+	s := NewScheduler()
+	var ejecuciones int
+
+	s.Set("evento", time.Now().Add(50*time.Millisecond), func(ctx context.Context) {
+		ejecuciones++
+	})
+	disparado := make(chan struct{})
+	s.Set("evento", time.Now().Add(10*time.Millisecond), func(ctx context.Context) {
+		ejecuciones++
+		close(disparado)
+	})
+
+	select {
+	case <-disparado:
+	case <-time.After(time.Second):
+		t.Fatal("la segunda acción no se ejecutó a tiempo")
+	}
+
+	// Dar tiempo de sobra a que la primera acción hubiera disparado si no
+	// hubiera sido reemplazada.
+	time.Sleep(100 * time.Millisecond)
+	if ejecuciones != 1 {
+		t.Errorf("se esperaba 1 ejecución, hubo %d", ejecuciones)
+	}
+}
+
+func TestSchedulerRescheduleMueveLaAccionPendiente(t *testing.T) {
+	s := NewScheduler()
+	disparado := make(chan struct{})
+
+	s.Set("evento", time.Now().Add(time.Hour), func(ctx context.Context) {
+		close(disparado)
+	})
+
+	if err := s.Reschedule("evento", time.Now().Add(10*time.Millisecond)); err != nil {
+		t.Fatalf("Reschedule devolvió error: %v", err)
+	}
+
+	select {
+	case <-disparado:
+	case <-time.After(time.Second):
+		t.Fatal("la acción reprogramada no se ejecutó a tiempo")
+	}
+}
+
+func TestSchedulerRescheduleSinIdPendienteDevuelveError(t *testing.T) {
+	s := NewScheduler()
+	if err := s.Reschedule("no-existe", time.Now()); err == nil {
+		t.Error("se esperaba un error al reprogramar un id inexistente")
+	}
+}
+
+func TestSchedulerShutdownEsperaAccionesEnCurso(t *testing.T) {
+	s := NewScheduler()
+	liberar := make(chan struct{})
+
+	s.Set("evento", time.Now(), func(ctx context.Context) {
+		<-liberar
+	})
+
+	// Dar tiempo a que el temporizador dispare y la acción empiece a correr.
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancelar := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelar()
+	if err := s.Shutdown(ctx); err == nil {
+		t.Error("Shutdown debería haber expirado mientras la acción seguía en curso")
+	}
+
+	close(liberar)
+
+	ctx2, cancelar2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancelar2()
+	if err := s.Shutdown(ctx2); err != nil {
+		t.Errorf("Shutdown no debería fallar una vez liberada la acción: %v", err)
+	}
+}
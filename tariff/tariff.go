@@ -0,0 +1,127 @@
+// Package tariff calcula el precio total de la energía, incluyendo los
+// cargos fijos, impuestos y las franjas horarias (peak/off-peak/super-off-peak)
+// típicas de tarifas reguladas como la PVPC española. SmartPlugScheduler.go usa este
+// paquete para optimizar sobre el coste total en lugar de sobre el precio
+// mayorista en bruto.
+package tariff
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Zone representa una franja horaria con un recargo fijo por kWh, válida
+// únicamente para los días de la semana indicados en Weekdays.
+type Zone struct {
+	Weekdays        []time.Weekday `yaml:"weekdays"`
+	Start           string         `yaml:"start"` // "HH:MM"
+	End             string         `yaml:"end"`   // "HH:MM", puede ser menor que Start para cruzar medianoche
+	SurchargeEurKwh float64        `yaml:"surcharge_eur_kwh"`
+}
+
+// Embed agrupa los cargos fijos, el impuesto aplicable y las zonas horarias
+// necesarias para calcular el precio total de la energía.
+type Embed struct {
+	Charges float64 `yaml:"charges"`
+	Tax     float64 `yaml:"tax"`
+	Zones   []Zone  `yaml:"zones"`
+}
+
+// config es la forma del fichero YAML de configuración de tarifas.
+type config struct {
+	Charges float64 `yaml:"charges"`
+	Tax     float64 `yaml:"tax"`
+	Zones   []Zone  `yaml:"zones"`
+}
+
+// LoadFromYAML lee la configuración de tarifa (cargos, impuesto y zonas)
+// desde un fichero YAML.
+func LoadFromYAML(path string) (*Embed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la configuración de tarifa: %w", err)
+	}
+
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error al parsear la configuración de tarifa: %w", err)
+	}
+
+	return &Embed{Charges: cfg.Charges, Tax: cfg.Tax, Zones: cfg.Zones}, nil
+}
+
+// LoadFromEnv construye un Embed a partir de las variables de entorno
+// TARIFF_CHARGES, TARIFF_TAX y, opcionalmente, TARIFF_ZONES_FILE apuntando a
+// un YAML con las zonas horarias. Si TARIFF_ZONES_FILE no está definida, el
+// Embed resultante no aplica ningún recargo por zona.
+func LoadFromEnv() (*Embed, error) {
+	embed := &Embed{}
+
+	if v := os.Getenv("TARIFF_CHARGES"); v != "" {
+		if _, err := fmt.Sscanf(v, "%f", &embed.Charges); err != nil {
+			return nil, fmt.Errorf("TARIFF_CHARGES inválido: %w", err)
+		}
+	}
+	if v := os.Getenv("TARIFF_TAX"); v != "" {
+		if _, err := fmt.Sscanf(v, "%f", &embed.Tax); err != nil {
+			return nil, fmt.Errorf("TARIFF_TAX inválido: %w", err)
+		}
+	}
+
+	if zonesFile := os.Getenv("TARIFF_ZONES_FILE"); zonesFile != "" {
+		fromFile, err := LoadFromYAML(zonesFile)
+		if err != nil {
+			return nil, err
+		}
+		embed.Zones = fromFile.Zones
+	}
+
+	return embed, nil
+}
+
+// zoneCharge devuelve el recargo por kWh de la primera zona que cubre hour,
+// o 0 si ninguna zona aplica en ese momento.
+func (e *Embed) zoneCharge(hour time.Time) float64 {
+	for _, zone := range e.Zones {
+		if zone.covers(hour) {
+			return zone.SurchargeEurKwh
+		}
+	}
+	return 0
+}
+
+// covers indica si hour cae dentro de esta zona, teniendo en cuenta tanto el
+// día de la semana como franjas que cruzan la medianoche (Start > End).
+func (z Zone) covers(hour time.Time) bool {
+	if len(z.Weekdays) > 0 {
+		dia := hour.Weekday()
+		encontrado := false
+		for _, d := range z.Weekdays {
+			if d == dia {
+				encontrado = true
+				break
+			}
+		}
+		if !encontrado {
+			return false
+		}
+	}
+
+	actual := hour.Format("15:04")
+	if z.Start <= z.End {
+		return actual >= z.Start && actual < z.End
+	}
+	// La franja cruza la medianoche, p. ej. 23:00-07:00.
+	return actual >= z.Start || actual < z.End
+}
+
+// TotalPrice devuelve el precio final de la energía para hour: el precio
+// mayorista más los cargos fijos y el recargo de zona correspondiente,
+// incrementado por el impuesto.
+func (e *Embed) TotalPrice(hour time.Time, wholesale float64) float64 {
+	base := wholesale + e.Charges + e.zoneCharge(hour)
+	return base * (1 + e.Tax)
+}
@@ -0,0 +1,76 @@
+package tariff
+
+import (
+	"testing"
+	"time"
+)
+
+func hora(t *testing.T, valor string) time.Time {
+	t.Helper()
+	instante, err := time.Parse("2006-01-02T15:04:05", valor)
+	if err != nil {
+		t.Fatalf("no se pudo parsear %q: %v", valor, err)
+	}
+	return instante
+}
+
+func TestZoneCoversFranjaNormal(t *testing.T) {
+	zone := Zone{Start: "10:00", End: "14:00"}
+
+	// 2024-01-01 es lunes.
+	if !zone.covers(hora(t, "2024-01-01T10:00:00")) {
+		t.Error("la hora de inicio debería estar cubierta")
+	}
+	if zone.covers(hora(t, "2024-01-01T14:00:00")) {
+		t.Error("la hora de fin es exclusiva y no debería estar cubierta")
+	}
+	if zone.covers(hora(t, "2024-01-01T09:59:00")) {
+		t.Error("una hora antes del inicio no debería estar cubierta")
+	}
+}
+
+func TestZoneCoversCruzaMedianoche(t *testing.T) {
+	zone := Zone{Start: "23:00", End: "07:00"}
+
+	if !zone.covers(hora(t, "2024-01-01T23:30:00")) {
+		t.Error("debería cubrir una hora posterior al inicio, antes de la medianoche")
+	}
+	if !zone.covers(hora(t, "2024-01-02T06:00:00")) {
+		t.Error("debería cubrir una hora antes del fin, ya en el día siguiente")
+	}
+	if zone.covers(hora(t, "2024-01-01T12:00:00")) {
+		t.Error("el mediodía no debería estar cubierto por una franja nocturna")
+	}
+}
+
+func TestZoneCoversFiltraPorDiaDeLaSemana(t *testing.T) {
+	zone := Zone{Weekdays: []time.Weekday{time.Saturday, time.Sunday}, Start: "00:00", End: "23:59"}
+
+	// 2024-01-01 es lunes, 2024-01-06 es sábado.
+	if zone.covers(hora(t, "2024-01-01T12:00:00")) {
+		t.Error("un lunes no debería estar cubierto por una zona de fin de semana")
+	}
+	if !zone.covers(hora(t, "2024-01-06T12:00:00")) {
+		t.Error("un sábado sí debería estar cubierto")
+	}
+}
+
+func TestTotalPriceAplicaRecargosEImpuesto(t *testing.T) {
+	embed := &Embed{
+		Charges: 0.05,
+		Tax:     0.21,
+		Zones:   []Zone{{Start: "10:00", End: "14:00", SurchargeEurKwh: 0.1}},
+	}
+
+	dentro := embed.TotalPrice(hora(t, "2024-01-01T11:00:00"), 0.2)
+	esperadoDentro := (0.2 + 0.05 + 0.1) * 1.21
+	if diff := dentro - esperadoDentro; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("TotalPrice dentro de zona = %v, esperado %v", dentro, esperadoDentro)
+	}
+
+	fuera := embed.TotalPrice(hora(t, "2024-01-01T20:00:00"), 0.2)
+	esperadoFuera := (0.2 + 0.05) * 1.21
+	if diff := fuera - esperadoFuera; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("TotalPrice fuera de zona = %v, esperado %v", fuera, esperadoFuera)
+	}
+}